@@ -1,50 +1,57 @@
 package main
 
 import (
-	"context"
-	"encoding/base64"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
-	authenticationv1 "k8s.io/api/authentication/v1"
-	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
-)
 
-var (
-	setupLog                = ctrl.Log.WithName("setup")
-	labels                  string
-	sveltosClusterNamespace string
-	sveltosClusterName      string
-	serviceAccountToken     bool
+	"github.com/projectsveltos/register-mgmt-cluster/controllers"
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/kubeconfig"
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/rbac"
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/registration"
 )
 
-const (
-	//nolint: gosec // this is just postfix of the secret name
-	sveltosKubeconfigSecretNamePostfix = "-sveltos-kubeconfig"
-	projectsveltos                     = "projectsveltos"
-	tokenExpirationInSeconds           = 7200
-	kubeconfigKey                      = "kubeconfig"
+var (
+	setupLog                    = ctrl.Log.WithName("setup")
+	labels                      string
+	sveltosClusterNamespace     string
+	sveltosClusterName          string
+	serviceAccountToken         bool
+	authMode                    string
+	proxyURL                    string
+	tlsServerName               string
+	serverOverride              string
+	clientCertSignerName        string
+	execCommand                 string
+	execArgs                    []string
+	execAPIVersion              string
+	execEnv                     map[string]string
+	oneshot                     bool
+	renewalThreshold            time.Duration
+	metricsBindAddress          string
+	healthProbeBindAddress      string
+	rbacMode                    string
+	customClusterRolePath       string
+	existingKubeconfigSelector  string
+	existingKubeconfigNamespace string
+	existingServiceAccount      string
 )
 
 func main() {
@@ -57,28 +64,14 @@ func main() {
 
 	ctrl.SetLogger(klog.Background())
 
-	scheme, err := initScheme()
-	if err != nil {
-		os.Exit(1)
-	}
-
-	restConfig := ctrl.GetConfigOrDie()
-
-	var c client.Client
-	c, err = client.New(restConfig, client.Options{Scheme: scheme})
-	if err != nil {
-		werr := fmt.Errorf("failed to connect: %w", err)
-		log.Fatal(werr)
-	}
-
-	caData, err := getCaData(setupLog)
-	if err != nil {
-		os.Exit(1)
+	if oneshot {
+		if err := runOnce(); err != nil {
+			os.Exit(1)
+		}
+		return
 	}
 
-	ctx := ctrl.SetupSignalHandler()
-	err = registerManagementCluster(ctx, restConfig, c, caData, setupLog)
-	if err != nil {
+	if err := runController(); err != nil {
 		os.Exit(1)
 	}
 }
@@ -111,394 +104,284 @@ func initFlags(fs *pflag.FlagSet) {
 		"This option allows you to specify the name of the SveltosCluster instance representing the management cluster")
 
 	fs.BoolVar(&serviceAccountToken, "service-account-token", false,
-		"This option instructs Sveltos to create a Secret of type kubernetes.io/service-account-token instead of generating a token associated to ServiceAccount")
+		"Deprecated: use --auth-mode=service-account-token instead. This option instructs Sveltos to create "+
+			"a Secret of type kubernetes.io/service-account-token instead of generating a token associated to ServiceAccount")
+
+	fs.StringVar(&authMode, "auth-mode", string(kubeconfig.TokenRequestAuthMode),
+		fmt.Sprintf("This option selects how the generated kubeconfig authenticates to the management cluster. "+
+			"One of %q (bound token via the TokenRequest API), %q (long-lived ServiceAccount token), "+
+			"%q (client certificate issued by the management cluster's signer) or %q (delegates to an external "+
+			"credential plugin).", kubeconfig.TokenRequestAuthMode, kubeconfig.ServiceAccountTokenAuthMode,
+			kubeconfig.ClientCertAuthMode, kubeconfig.ExecAuthMode))
+
+	fs.StringVar(&proxyURL, "proxy-url", "",
+		"This option allows you to specify a proxy-url to reach the management cluster API server, "+
+			"set on the generated kubeconfig's cluster entry")
+
+	fs.StringVar(&tlsServerName, "tls-server-name", "",
+		"This option overrides the server name used to verify the certificate presented by the management "+
+			"cluster API server, set on the generated kubeconfig's cluster entry")
+
+	fs.StringVar(&serverOverride, "server-override", "",
+		"This option allows you to specify the API server endpoint to embed in the generated kubeconfig. "+
+			"It defaults to the endpoint this tool itself uses, which is often an in-cluster ClusterIP unreachable "+
+			"from workload clusters; use this to point at a front-proxy or load balancer endpoint instead")
+
+	fs.StringVar(&clientCertSignerName, "client-cert-signer-name", "kubernetes.io/kube-apiserver-client",
+		"This option selects the signer used to issue the client certificate when --auth-mode=client-cert is set")
+
+	fs.StringVar(&execCommand, "exec-command", "",
+		"This option sets the command to exec when --auth-mode=exec is set, e.g. \"aws\" or \"gcloud\"")
+
+	fs.StringArrayVar(&execArgs, "exec-arg", nil,
+		"This option appends an argument to the command set by --exec-command. Can be specified multiple times, "+
+			"e.g. --exec-arg=eks --exec-arg=get-token --exec-arg=--cluster-name=my-cluster")
+
+	fs.StringVar(&execAPIVersion, "exec-api-version", "client.authentication.k8s.io/v1beta1",
+		"This option sets the client.authentication.k8s.io API version implemented by the --exec-command plugin")
+
+	fs.StringToStringVar(&execEnv, "exec-env", nil,
+		"This option sets additional environment variables passed to the --exec-command plugin, "+
+			"in the form <key1=value1,key2=value2>")
+
+	fs.BoolVar(&oneshot, "oneshot", true,
+		"This option instructs the tool to register the management cluster once and exit, the original "+
+			"CronJob-style behavior. Set to false to instead run as a long-lived controller that keeps the "+
+			"ServiceAccount, RBAC and kubeconfig Secret in sync and rotates the token before it expires")
+
+	fs.DurationVar(&renewalThreshold, "renewal-threshold", 30*time.Minute,
+		"This option is only used when --oneshot=false. It sets how long before the current token expires the "+
+			"controller rotates it")
+
+	fs.StringVar(&metricsBindAddress, "metrics-bind-address", ":8080",
+		"This option is only used when --oneshot=false. It sets the address the metrics endpoint binds to")
+
+	fs.StringVar(&healthProbeBindAddress, "health-probe-bind-address", ":8081",
+		"This option is only used when --oneshot=false. It sets the address the /healthz and /readyz endpoints bind to")
+
+	fs.StringVar(&rbacMode, "rbac-mode", string(rbac.SveltosScopedMode),
+		fmt.Sprintf("This option selects how the ClusterRole granted to the management cluster ServiceAccount is "+
+			"computed. One of %q (verbs=*, apiGroups=*, resources=*, the original behavior), %q (union of the "+
+			"installed Sveltos component ClusterRoles plus the minimal onboarding rules) or %q (read Rules from "+
+			"the ClusterRole manifest at --custom-clusterrole-path).", rbac.ClusterAdminMode, rbac.SveltosScopedMode,
+			rbac.CustomMode))
+
+	fs.StringVar(&customClusterRolePath, "custom-clusterrole-path", "",
+		"This option is only used when --rbac-mode=custom. It points at a ClusterRole YAML manifest whose Rules "+
+			"are granted to the management cluster ServiceAccount")
+
+	fs.StringVar(&existingKubeconfigSelector, "existing-kubeconfig-selector", "",
+		"This option enables \"bring your own credentials\" registration. When set, the tool looks up, in "+
+			"--existing-kubeconfig-namespace, exactly one Secret matching this label selector "+
+			"(e.g. \"sveltos.projectsveltos.io/mgmt-kubeconfig=true\"), validates it holds a working kubeconfig "+
+			"under its \"kubeconfig\" key, and adopts it instead of creating a ServiceAccount/RBAC/token of its own")
+
+	fs.StringVar(&existingKubeconfigNamespace, "existing-kubeconfig-namespace", registration.ProjectSveltos,
+		"This option is only used when --existing-kubeconfig-selector is set. It sets the namespace searched for "+
+			"the matching Secret")
+
+	fs.StringVar(&existingServiceAccount, "existing-serviceaccount", "",
+		"This option enables reusing an already-provisioned ServiceAccount, in the form <namespace>/<name>, that "+
+			"the operator has granted its own RBAC. The tool only mints a token for it; it does not create or "+
+			"modify any ServiceAccount, ClusterRole or ClusterRoleBinding")
 }
 
-func registerManagementCluster(ctx context.Context, restConfig *rest.Config, c client.Client,
-	caData []byte, logger logr.Logger) error {
-
-	kubeconfig, err := generateKubeconfigForServiceAccount(ctx, restConfig, c, projectsveltos,
-		projectsveltos, tokenExpirationInSeconds, caData, logger)
-	if err != nil {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get kubeconfig: %v", err))
-		return err
-	}
-
-	var sveltosClusterLabels map[string]string
-	sveltosClusterLabels, err = stringToMap(labels)
-	if err != nil {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to parse labels: %v", err))
-		return err
-	}
-
-	err = onboardManagementCluster(ctx, c, sveltosClusterNamespace, sveltosClusterName, kubeconfig,
-		sveltosClusterLabels, logger)
-	if err != nil {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to register cluster: %v", err))
-		return err
+func authOptionsFromFlags() registration.AuthOptions {
+	mode := kubeconfig.AuthMode(authMode)
+	if serviceAccountToken && mode == kubeconfig.TokenRequestAuthMode {
+		// --service-account-token is deprecated in favor of --auth-mode, but keep
+		// honoring it when the caller did not explicitly pick a mode.
+		mode = kubeconfig.ServiceAccountTokenAuthMode
+	}
+
+	return registration.AuthOptions{
+		Mode:                 mode,
+		ProxyURL:             proxyURL,
+		TLSServerName:        tlsServerName,
+		ServerOverride:       serverOverride,
+		ClientCertSignerName: clientCertSignerName,
+		Exec: kubeconfig.ExecConfig{
+			APIVersion: execAPIVersion,
+			Command:    execCommand,
+			Args:       execArgs,
+			Env:        execEnv,
+		},
 	}
-
-	return nil
 }
 
-func stringToMap(data string) (map[string]string, error) {
-	if data == "" {
-		return nil, nil
+func rbacOptionsFromFlags() rbac.Options {
+	return rbac.Options{
+		Mode:               rbac.Mode(rbacMode),
+		CustomManifestPath: customClusterRolePath,
 	}
-
-	const keyValueLength = 2
-	result := make(map[string]string)
-	for _, pair := range strings.Split(data, ",") {
-		kv := strings.Split(pair, "=")
-		if len(kv) != keyValueLength {
-			return nil, fmt.Errorf("invalid key-value pair format: %s", pair)
-		}
-		key := strings.TrimSpace(kv[0])
-		value := strings.TrimSpace(kv[1])
-		result[key] = value
-	}
-	return result, nil
 }
 
-func generateKubeconfigForServiceAccount(ctx context.Context, restConfig *rest.Config, c client.Client,
-	namespace, serviceAccountName string, expirationSeconds int, caData []byte, logger logr.Logger) (string, error) {
-
-	if err := createNamespace(ctx, c, namespace, logger); err != nil {
-		return "", err
-	}
-	if err := createServiceAccount(ctx, c, namespace, serviceAccountName, logger); err != nil {
-		return "", err
-	}
-	if err := createClusterRole(ctx, c, projectsveltos, logger); err != nil {
-		return "", err
-	}
-	if err := createClusterRoleBinding(ctx, c, projectsveltos, projectsveltos, namespace, serviceAccountName, logger); err != nil {
-		return "", err
-	}
-
-	var token string
-	if serviceAccountToken {
-		if err := createSecret(ctx, c, namespace, serviceAccountName, logger); err != nil {
-			return "", err
-		}
-		var err error
-		token, err = getToken(ctx, c, namespace, serviceAccountName)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		tokenRequest, err := getServiceAccountTokenRequest(ctx, restConfig, namespace, serviceAccountName, expirationSeconds, logger)
-		if err != nil {
-			return "", err
-		}
-		token = tokenRequest.Token
+func existingCredentialsOptionsFromFlags() (registration.ExistingCredentialsOptions, error) {
+	saNamespace, saName, err := registration.ParseExistingServiceAccount(existingServiceAccount)
+	if err != nil {
+		return registration.ExistingCredentialsOptions{}, err
 	}
 
-	logger.V(logs.LogInfo).Info("Get Kubeconfig from TokenRequest")
-	data := getKubeconfigFromToken(restConfig, namespace, serviceAccountName, token, caData)
-
-	return data, nil
+	return registration.ExistingCredentialsOptions{
+		KubeconfigSelectorNamespace:     existingKubeconfigNamespace,
+		KubeconfigSelector:              existingKubeconfigSelector,
+		ExistingServiceAccountNamespace: saNamespace,
+		ExistingServiceAccountName:      saName,
+	}, nil
 }
 
-func createNamespace(ctx context.Context, c client.Client, name string, logger logr.Logger) error {
-	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create namespace %s", name))
-	currentNs := &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
-		},
-	}
-	err := c.Create(ctx, currentNs)
-	if err != nil && !apierrors.IsAlreadyExists(err) {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create Namespace %s: %v",
-			name, err))
+// runOnce is the original CronJob-style behavior: register the management cluster once
+// and return.
+func runOnce() error {
+	scheme, err := initScheme()
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func createServiceAccount(ctx context.Context, c client.Client, namespace, name string,
-	logger logr.Logger) error {
+	restConfig := ctrl.GetConfigOrDie()
 
-	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create serviceAccount %s/%s", namespace, name))
-	currentSA := &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
+	var c client.Client
+	c, err = client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		werr := fmt.Errorf("failed to connect: %w", err)
+		log.Fatal(werr)
 	}
 
-	err := c.Create(ctx, currentSA)
-	if err != nil && !apierrors.IsAlreadyExists(err) {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create ServiceAccount %s/%s: %v",
-			namespace, name, err))
+	caData, err := getCaData(setupLog)
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func createSecret(ctx context.Context, c client.Client, namespace, saName string,
-	logger logr.Logger) error {
-
-	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create Secret %s/%s", namespace, saName))
-	currentSecret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      saName,
-			Annotations: map[string]string{
-				corev1.ServiceAccountNameKey: saName,
-			},
-		},
-		Type: corev1.SecretTypeServiceAccountToken,
-	}
+	ctx := ctrl.SetupSignalHandler()
 
-	err := c.Create(ctx, currentSecret)
-	if err != nil && !apierrors.IsAlreadyExists(err) {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create Secret %s/%s: %v",
-			namespace, saName, err))
+	existingOpts, err := existingCredentialsOptionsFromFlags()
+	if err != nil {
+		setupLog.V(logs.LogInfo).Info(fmt.Sprintf("invalid existing credentials flags: %v", err))
 		return err
 	}
 
-	return nil
-}
-
-func getToken(ctx context.Context, c client.Client, namespace, secretName string) (string, error) {
-	retries := 0
-	const maxRetries = 5
-	for {
-		secret := &corev1.Secret{}
-		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretName},
-			secret)
-		if err != nil {
-			if retries < maxRetries {
-				time.Sleep(time.Second)
-				continue
-			}
-			return "", err
-		}
-
-		if secret.Data == nil {
-			time.Sleep(time.Second)
-			continue
-		}
-
-		v, ok := secret.Data["token"]
-		if !ok {
-			time.Sleep(time.Second)
-			continue
-		}
-
-		return string(v), nil
+	var kubeconfigData string
+	var enableRenewal bool
+	if existingOpts.Enabled() {
+		kubeconfigData, err = registration.GenerateKubeconfigFromExistingCredentials(ctx, restConfig, c,
+			registration.TokenExpirationInSeconds, caData, existingOpts, setupLog)
+		enableRenewal = !existingOpts.DisablesTokenRenewal()
+	} else {
+		authOpts := authOptionsFromFlags()
+		kubeconfigData, err = registration.GenerateKubeconfigForServiceAccount(ctx, restConfig, c,
+			registration.ProjectSveltos, registration.ProjectSveltos, registration.TokenExpirationInSeconds,
+			caData, authOpts, rbacOptionsFromFlags(), setupLog)
+		// client-cert/exec kubeconfigs carry no bearer token for Sveltos to refresh via
+		// TokenRequest; enabling renewal for them would have Sveltos overwrite the
+		// credential with a freshly minted token, destroying it.
+		enableRenewal = authOpts.Mode.UsesBearerToken()
 	}
-}
-
-func createClusterRole(ctx context.Context, c client.Client, clusterRoleName string, logger logr.Logger) error {
-	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create ClusterRole %s", clusterRoleName))
-	// Extends permission in addon-controller-role-extra
-	clusterrole := &rbacv1.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: clusterRoleName,
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				Verbs:     []string{"*"},
-				APIGroups: []string{"*"},
-				Resources: []string{"*"},
-			},
-			{
-				Verbs:           []string{"*"},
-				NonResourceURLs: []string{"*"},
-			},
-		},
+	if err != nil {
+		setupLog.V(logs.LogInfo).Info(fmt.Sprintf("failed to get kubeconfig: %v", err))
+		return err
 	}
 
-	err := c.Create(ctx, clusterrole)
-	if err != nil && !apierrors.IsAlreadyExists(err) {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create ClusterRole %s: %v",
-			clusterRoleName, err))
+	sveltosClusterLabels, err := registration.StringToMap(labels)
+	if err != nil {
+		setupLog.V(logs.LogInfo).Info(fmt.Sprintf("failed to parse labels: %v", err))
 		return err
 	}
 
-	return nil
-}
-
-func createClusterRoleBinding(ctx context.Context, c client.Client,
-	clusterRoleName, clusterRoleBindingName, serviceAccountNamespace, serviceAccountName string, logger logr.Logger) error {
-
-	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create ClusterRoleBinding %s", clusterRoleBindingName))
-	clusterrolebinding := &rbacv1.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: clusterRoleBindingName,
-		},
-		RoleRef: rbacv1.RoleRef{
-			APIGroup: rbacv1.SchemeGroupVersion.Group,
-			Kind:     "ClusterRole",
-			Name:     clusterRoleName,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Namespace: serviceAccountNamespace,
-				Name:      serviceAccountName,
-				Kind:      "ServiceAccount",
-				APIGroup:  corev1.SchemeGroupVersion.Group,
-			},
-		},
-	}
-	err := c.Create(ctx, clusterrolebinding)
-	if err != nil && !apierrors.IsAlreadyExists(err) {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create clusterrolebinding %s: %v",
-			clusterRoleBindingName, err))
+	err = registration.OnboardManagementCluster(ctx, c, sveltosClusterNamespace, sveltosClusterName,
+		kubeconfigData, sveltosClusterLabels, enableRenewal, existingOpts.KubeconfigSelector != "",
+		setupLog)
+	if err != nil {
+		setupLog.V(logs.LogInfo).Info(fmt.Sprintf("failed to register cluster: %v", err))
 		return err
 	}
 
 	return nil
 }
 
-// getServiceAccountTokenRequest returns token for a serviceaccount
-func getServiceAccountTokenRequest(ctx context.Context, restConfig *rest.Config, serviceAccountNamespace, serviceAccountName string,
-	expirationSeconds int, logger logr.Logger) (*authenticationv1.TokenRequestStatus, error) {
-
-	expiration := int64(expirationSeconds)
-
-	treq := &authenticationv1.TokenRequest{}
-
-	if expirationSeconds != 0 {
-		treq.Spec = authenticationv1.TokenRequestSpec{
-			ExpirationSeconds: &expiration,
-		}
-	}
-
-	clientset, err := kubernetes.NewForConfig(restConfig)
+// runController runs as a long-lived controller-runtime manager, reconciling the
+// management cluster's SveltosCluster and rotating its token as it approaches expiration.
+func runController() error {
+	existingOpts, err := existingCredentialsOptionsFromFlags()
 	if err != nil {
-		return nil, err
+		setupLog.V(logs.LogInfo).Info(fmt.Sprintf("invalid existing credentials flags: %v", err))
+		return err
+	}
+	if existingOpts.Enabled() {
+		// The reconciler only knows how to mint and rotate its own ServiceAccount/RBAC/
+		// token; it has no notion of an externally supplied or adopted credential to
+		// reconcile against. Rather than silently ignoring these flags and minting Sveltos-
+		// managed credentials anyway, refuse to start.
+		err := fmt.Errorf("--existing-kubeconfig-selector and --existing-serviceaccount are only supported " +
+			"with --oneshot=true; controller mode (--oneshot=false) does not support bring-your-own-credentials registration")
+		setupLog.Error(err, "unsupported flag combination")
+		return err
 	}
 
-	logger.V(logs.LogInfo).Info(
-		fmt.Sprintf("Create Token for ServiceAccount %s/%s", serviceAccountNamespace, serviceAccountName))
-	var tokenRequest *authenticationv1.TokenRequest
-	tokenRequest, err = clientset.CoreV1().ServiceAccounts(serviceAccountNamespace).
-		CreateToken(ctx, serviceAccountName, treq, metav1.CreateOptions{})
+	scheme, err := initScheme()
 	if err != nil {
-		logger.V(logs.LogInfo).Info(
-			fmt.Sprintf("Failed to create token for ServiceAccount %s/%s: %v",
-				serviceAccountNamespace, serviceAccountName, err))
-		return nil, err
+		return err
 	}
 
-	return &tokenRequest.Status, nil
-}
-
-// getKubeconfigFromToken returns Kubeconfig to access management cluster from token.
-func getKubeconfigFromToken(restConfig *rest.Config, namespace, serviceAccountName, token string, caData []byte) string {
-	template := `apiVersion: v1
-kind: Config
-clusters:
-- name: local
-  cluster:
-    server: %s
-    certificate-authority-data: "%s"
-users:
-- name: %s
-  user:
-    token: %s
-contexts:
-- name: sveltos-context
-  context:
-    cluster: local
-    namespace: %s
-    user: %s
-current-context: sveltos-context`
-
-	data := fmt.Sprintf(template, restConfig.Host,
-		base64.StdEncoding.EncodeToString(caData), serviceAccountName, token, namespace, serviceAccountName)
-
-	return data
-}
-
-func onboardManagementCluster(ctx context.Context, c client.Client, clusterNamespace, clusterName, kubeconfigData string,
-	labels map[string]string, logger logr.Logger) error {
+	restConfig := ctrl.GetConfigOrDie()
 
-	err := createNamespace(ctx, c, clusterNamespace, logger)
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: metricsBindAddress},
+		HealthProbeBindAddress: healthProbeBindAddress,
+	})
 	if err != nil {
+		setupLog.Error(err, "unable to start manager")
 		return err
 	}
 
-	err = patchSveltosCluster(ctx, c, clusterNamespace, clusterName, labels, logger)
+	caData, err := getCaData(setupLog)
 	if err != nil {
 		return err
 	}
 
-	secretName := clusterName + sveltosKubeconfigSecretNamePostfix
-	return patchSecret(ctx, c, clusterNamespace, secretName, kubeconfigData, logger)
-}
-
-func patchSveltosCluster(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
-	labels map[string]string, logger logr.Logger) error {
-
-	// Token duration is fixed at one hour.  Increasing this value would cause issues
-	// because Sveltos relies on this duration to determine when to refresh the token.
-	// If this value is larger than the actual token expiration (set by previous released
-	// images), Sveltos might attempt to use an expired token, leading to authentication failures.
-	// This value must match the duration of the renewed tokens provided by the shipped version.
-	const renewalInterval = 1 * 3600 * time.Second // every 1 hour
-	currentSveltosCluster := &libsveltosv1beta1.SveltosCluster{}
-	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName},
-		currentSveltosCluster)
+	sveltosClusterLabels, err := registration.StringToMap(labels)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.V(logs.LogInfo).Info(fmt.Sprintf("Creating SveltosCluster %s/%s", clusterNamespace, clusterName))
-			currentSveltosCluster.Namespace = clusterNamespace
-			currentSveltosCluster.Name = clusterName
-			currentSveltosCluster.Labels = labels
-			currentSveltosCluster.Spec = libsveltosv1beta1.SveltosClusterSpec{
-				TokenRequestRenewalOption: &libsveltosv1beta1.TokenRequestRenewalOption{
-					RenewTokenRequestInterval: metav1.Duration{Duration: renewalInterval},
-				},
-			}
-			return c.Create(ctx, currentSveltosCluster)
-		}
+		setupLog.V(logs.LogInfo).Info(fmt.Sprintf("failed to parse labels: %v", err))
 		return err
 	}
 
-	logger.V(logs.LogInfo).Info("Updating SveltosCluster")
-	if currentSveltosCluster.Labels == nil {
-		currentSveltosCluster.Labels = map[string]string{}
-	}
-	for k := range labels {
-		currentSveltosCluster.Labels[k] = labels[k]
+	reconciler := &controllers.ManagementClusterRegistrationReconciler{
+		Client:                  mgr.GetClient(),
+		RestConfig:              restConfig,
+		Recorder:                mgr.GetEventRecorderFor("register-mgmt-cluster"),
+		CAData:                  caData,
+		ClusterNamespace:        sveltosClusterNamespace,
+		ClusterName:             sveltosClusterName,
+		ServiceAccountNamespace: registration.ProjectSveltos,
+		ServiceAccountName:      registration.ProjectSveltos,
+		ExpirationSeconds:       registration.TokenExpirationInSeconds,
+		RenewalThreshold:        renewalThreshold,
+		Labels:                  sveltosClusterLabels,
+		AuthOptions:             authOptionsFromFlags(),
+		RBACOptions:             rbacOptionsFromFlags(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("ManagementClusterRegistration"),
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ManagementClusterRegistration")
+		return err
 	}
 
-	currentSveltosCluster.Spec = libsveltosv1beta1.SveltosClusterSpec{
-		TokenRequestRenewalOption: &libsveltosv1beta1.TokenRequestRenewalOption{
-			RenewTokenRequestInterval: metav1.Duration{Duration: renewalInterval},
-		},
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		return err
 	}
-	return c.Update(ctx, currentSveltosCluster)
-}
-
-func patchSecret(ctx context.Context, c client.Client, clusterNamespace, secretName, kubeconfigData string,
-	logger logr.Logger) error {
-
-	currentSecret := &corev1.Secret{}
-	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: secretName}, currentSecret)
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.V(logs.LogInfo).Info(fmt.Sprintf("Creating Secret %s/%s", clusterNamespace, secretName))
-			currentSecret.Namespace = clusterNamespace
-			currentSecret.Name = secretName
-			currentSecret.Data = map[string][]byte{kubeconfigKey: []byte(kubeconfigData)}
-			return c.Create(ctx, currentSecret)
-		}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
 		return err
 	}
 
-	logger.V(logs.LogInfo).Info(fmt.Sprintf("Updating Secret %s/%s", clusterNamespace, secretName))
-	currentSecret.Data = map[string][]byte{
-		kubeconfigKey: []byte(kubeconfigData),
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		return err
 	}
 
-	return c.Update(ctx, currentSecret)
+	return nil
 }
 
 func getCaData(logger logr.Logger) ([]byte, error) {
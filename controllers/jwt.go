@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// tokenFromKubeconfig parses a kubeconfig and returns the bearer token of its current
+// context's AuthInfo.
+func tokenFromKubeconfig(data []byte) (string, error) {
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	context, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return "", fmt.Errorf("kubeconfig has no context %q", cfg.CurrentContext)
+	}
+
+	authInfo, ok := cfg.AuthInfos[context.AuthInfo]
+	if !ok {
+		return "", fmt.Errorf("kubeconfig has no user %q", context.AuthInfo)
+	}
+
+	if authInfo.Token == "" {
+		return "", fmt.Errorf("kubeconfig user %q has no bearer token", context.AuthInfo)
+	}
+
+	return authInfo.Token, nil
+}
+
+// tokenExpiration returns the exp claim of a JWT bearer token, without verifying its
+// signature: the controller only needs to know when the token it itself requested expires,
+// and trusts the cluster it is talking to.
+func tokenExpiration(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	const jwtParts = 3
+	if len(parts) != jwtParts {
+		return time.Time{}, fmt.Errorf("token is not a well-formed JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("failed to unmarshal token claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("token has no exp claim")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func jwtWithExp(exp int64) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp)))
+	return header + "." + payload + ".signature"
+}
+
+func TestTokenExpiration(t *testing.T) {
+	want := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := jwtWithExp(want.Unix())
+
+	got, err := tokenExpiration(token)
+	if err != nil {
+		t.Fatalf("tokenExpiration returned error: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Fatalf("expected exp %v, got %v", want, got)
+	}
+}
+
+func TestTokenExpirationRejectsMalformedToken(t *testing.T) {
+	if _, err := tokenExpiration("not-a-jwt"); err == nil {
+		t.Fatal("expected error for malformed token")
+	}
+}
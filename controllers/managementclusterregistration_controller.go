@@ -0,0 +1,315 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controllers contains the ManagementClusterRegistration reconciler: the
+// controller-runtime driven equivalent of the one-shot registration performed by
+// register-mgmt-cluster's --oneshot mode.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/rbac"
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/registration"
+)
+
+const (
+	reasonRotated      = "Rotated"
+	reasonUpToDate     = "UpToDate"
+	reasonRotateFailed = "RotationFailed"
+
+	// lastRotationAnnotation records, on the kubeconfig Secret, when a non-token-based
+	// credential (client-cert, exec) was last rotated. Those auth modes carry no bearer
+	// token to read an expiration from, so rotation timing is tracked explicitly instead.
+	lastRotationAnnotation = "config.projectsveltos.io/credential-rotated-at"
+)
+
+// ManagementClusterRegistrationReconciler reconciles the SveltosCluster representing the
+// management cluster: it ensures the SveltosCluster itself, plus the ServiceAccount/RBAC/
+// kubeconfig Secret it needs, exist, and rotates the embedded credential before it expires.
+type ManagementClusterRegistrationReconciler struct {
+	client.Client
+	RestConfig *rest.Config
+	Log        logr.Logger
+	// Recorder emits Events on the SveltosCluster reporting rotation outcomes. libsveltos'
+	// SveltosClusterStatus has no Conditions field, so Events plus Status.Ready are how this
+	// reconciler surfaces rotation time, next rotation and failure reasons.
+	Recorder record.EventRecorder
+
+	CAData []byte
+
+	ClusterNamespace        string
+	ClusterName             string
+	ServiceAccountNamespace string
+	ServiceAccountName      string
+	ExpirationSeconds       int
+	RenewalThreshold        time.Duration
+	Labels                  map[string]string
+	AuthOptions             registration.AuthOptions
+	RBACOptions             rbac.Options
+}
+
+func (r *ManagementClusterRegistrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Reconcile only acts on the SveltosCluster named by --namespace/--name, so on a
+	// fresh management cluster where it does not exist yet, no watch event would ever fire
+	// and onboarding would never happen. Feed one synthetic event through the same
+	// workqueue the watch uses, instead of calling Reconcile directly off-queue: the
+	// workqueue serializes reconciles for the same object, so this can't race a
+	// watch-triggered reconcile fired by the SveltosCluster's own creation.
+	bootstrapCh := make(chan event.GenericEvent, 1)
+
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&libsveltosv1beta1.SveltosCluster{}).
+		Watches(&source.Channel{Source: bootstrapCh}, &handler.EnqueueRequestForObject{}).
+		Complete(r)
+	if err != nil {
+		return err
+	}
+
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return fmt.Errorf("failed waiting for cache sync")
+		}
+		bootstrapCh <- event.GenericEvent{
+			Object: &libsveltosv1beta1.SveltosCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: r.ClusterNamespace, Name: r.ClusterName},
+			},
+		}
+		return nil
+	}))
+}
+
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=sveltosclusters,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=lib.projectsveltos.io,resources=sveltosclusters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts;secrets,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=serviceaccounts/token,verbs=create
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *ManagementClusterRegistrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("sveltoscluster", req.NamespacedName)
+
+	if req.Namespace != r.ClusterNamespace || req.Name != r.ClusterName {
+		// This reconciler only registers one management cluster: the SveltosCluster
+		// identified by --namespace/--name. Other SveltosCluster instances (workload
+		// clusters) are reconciled by Sveltos itself, not by this tool.
+		return ctrl.Result{}, nil
+	}
+
+	sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+	exists := true
+	if err := r.Get(ctx, req.NamespacedName, sveltosCluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		// The SveltosCluster does not exist yet: onboard it now rather than deferring to
+		// a watch event that will never fire without it.
+		exists = false
+	}
+
+	secretName := r.ClusterName + registration.SveltosKubeconfigSecretNamePostfix
+
+	if exists {
+		expiration, err := r.currentCredentialExpiration(ctx, secretName)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("unable to read current credential expiration, rotating: %v", err))
+		} else if time.Until(expiration) > r.RenewalThreshold {
+			if condErr := r.setStatus(ctx, sveltosCluster, true, reasonUpToDate,
+				fmt.Sprintf("credential valid until %s, next rotation at %s", expiration.Format(time.RFC3339),
+					expiration.Add(-r.RenewalThreshold).Format(time.RFC3339))); condErr != nil {
+				logger.Error(condErr, "failed to update SveltosCluster status")
+			}
+			tokenExpirationSeconds.Set(float64(expiration.Unix()))
+			return ctrl.Result{RequeueAfter: time.Until(expiration.Add(-r.RenewalThreshold))}, nil
+		}
+	}
+
+	kubeconfigData, err := registration.GenerateKubeconfigForServiceAccount(ctx, r.RestConfig, r.Client,
+		r.ServiceAccountNamespace, r.ServiceAccountName, r.ExpirationSeconds, r.CAData, r.AuthOptions,
+		r.RBACOptions, logger)
+	if err != nil {
+		tokenRotationFailuresTotal.Inc()
+		if exists {
+			if condErr := r.setStatus(ctx, sveltosCluster, false, reasonRotateFailed, err.Error()); condErr != nil {
+				logger.Error(condErr, "failed to update SveltosCluster status")
+			}
+		}
+		return ctrl.Result{}, err
+	}
+
+	// client-cert/exec kubeconfigs carry no bearer token for Sveltos to refresh via
+	// TokenRequest; enabling renewal for them would have Sveltos overwrite the credential
+	// this reconciler just minted with a freshly minted token, destroying it.
+	if err := registration.OnboardManagementCluster(ctx, r.Client, r.ClusterNamespace, r.ClusterName,
+		kubeconfigData, r.Labels, r.usesBearerToken(), false, logger); err != nil {
+		tokenRotationFailuresTotal.Inc()
+		if exists {
+			if condErr := r.setStatus(ctx, sveltosCluster, false, reasonRotateFailed, err.Error()); condErr != nil {
+				logger.Error(condErr, "failed to update SveltosCluster status")
+			}
+		}
+		return ctrl.Result{}, err
+	}
+
+	tokenRotationsTotal.Inc()
+
+	if !r.usesBearerToken() {
+		if err := r.stampRotationTime(ctx, secretName, time.Now()); err != nil {
+			logger.Error(err, "rotated credential but failed to record rotation time")
+		}
+	}
+
+	// OnboardManagementCluster creates the SveltosCluster the first time through, and may
+	// have updated it even when it already existed, so re-fetch before patching status.
+	if err := r.Get(ctx, req.NamespacedName, sveltosCluster); err != nil {
+		logger.Error(err, "rotated credential but failed to read back SveltosCluster")
+		return ctrl.Result{RequeueAfter: r.RenewalThreshold}, nil
+	}
+
+	newExpiration, err := r.currentCredentialExpiration(ctx, secretName)
+	if err != nil {
+		logger.Error(err, "rotated credential but failed to read back its expiration")
+		return ctrl.Result{RequeueAfter: r.RenewalThreshold}, nil
+	}
+	tokenExpirationSeconds.Set(float64(newExpiration.Unix()))
+
+	if err := r.setStatus(ctx, sveltosCluster, true, reasonRotated,
+		fmt.Sprintf("credential rotated, valid until %s", newExpiration.Format(time.RFC3339))); err != nil {
+		logger.Error(err, "failed to update SveltosCluster status")
+	}
+
+	return ctrl.Result{RequeueAfter: time.Until(newExpiration.Add(-r.RenewalThreshold))}, nil
+}
+
+// usesBearerToken reports whether the configured auth mode embeds a bearer token whose exp
+// claim can be read directly. client-cert and exec kubeconfigs carry no such token.
+func (r *ManagementClusterRegistrationReconciler) usesBearerToken() bool {
+	return r.AuthOptions.Mode.UsesBearerToken()
+}
+
+// currentCredentialExpiration returns when the current kubeconfig Secret's credential
+// expires; Reconcile rotates it RenewalThreshold before that. For token-based auth modes this is the embedded token's exp claim; for
+// client-cert/exec modes, which carry no bearer token, it is derived from the lastRotationAnnotation
+// stamped by this reconciler plus registration.RenewalInterval.
+func (r *ManagementClusterRegistrationReconciler) currentCredentialExpiration(ctx context.Context, secretName string) (time.Time, error) {
+	if r.usesBearerToken() {
+		return r.currentTokenExpiration(ctx, secretName)
+	}
+	return r.lastRotationExpiration(ctx, secretName)
+}
+
+func (r *ManagementClusterRegistrationReconciler) currentTokenExpiration(ctx context.Context, secretName string) (time.Time, error) {
+	data, err := r.getSecretKubeconfig(ctx, secretName)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	token, err := tokenFromKubeconfig(data)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return tokenExpiration(token)
+}
+
+func (r *ManagementClusterRegistrationReconciler) lastRotationExpiration(ctx context.Context, secretName string) (time.Time, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: r.ClusterNamespace, Name: secretName}, secret); err != nil {
+		return time.Time{}, err
+	}
+
+	raw, ok := secret.Annotations[lastRotationAnnotation]
+	if !ok {
+		return time.Time{}, fmt.Errorf("secret %s/%s has no %s annotation", r.ClusterNamespace, secretName, lastRotationAnnotation)
+	}
+
+	rotatedAt, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("secret %s/%s has an invalid %s annotation: %w",
+			r.ClusterNamespace, secretName, lastRotationAnnotation, err)
+	}
+
+	return rotatedAt.Add(registration.RenewalInterval), nil
+}
+
+// stampRotationTime records when a non-token-based credential was rotated, so the next
+// reconcile knows when it is due again without needing to parse a bearer token.
+func (r *ManagementClusterRegistrationReconciler) stampRotationTime(ctx context.Context, secretName string, when time.Time) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: r.ClusterNamespace, Name: secretName}, secret); err != nil {
+		return err
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[lastRotationAnnotation] = when.Format(time.RFC3339)
+
+	return r.Update(ctx, secret)
+}
+
+func (r *ManagementClusterRegistrationReconciler) getSecretKubeconfig(ctx context.Context, secretName string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: r.ClusterNamespace, Name: secretName}, secret); err != nil {
+		return nil, err
+	}
+
+	data, ok := secret.Data[registration.KubeconfigKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no %s key", r.ClusterNamespace, secretName, registration.KubeconfigKey)
+	}
+
+	return data, nil
+}
+
+// setStatus records the reconcile outcome as an Event on sveltosCluster (carrying the
+// reason/message detail that libsveltos' SveltosClusterStatus has no Conditions field to
+// hold) and patches its Status.Ready accordingly.
+func (r *ManagementClusterRegistrationReconciler) setStatus(ctx context.Context,
+	sveltosCluster *libsveltosv1beta1.SveltosCluster, ready bool, reason, message string) error {
+
+	eventType := corev1.EventTypeNormal
+	if !ready {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Event(sveltosCluster, eventType, reason, message)
+
+	if sveltosCluster.Status.Ready == ready {
+		return nil
+	}
+
+	sveltosCluster.Status.Ready = ready
+	return r.Status().Update(ctx, sveltosCluster)
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	tokenRotationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sveltos_mgmt_cluster_token_rotations_total",
+		Help: "Total number of management cluster token rotations performed",
+	})
+
+	tokenRotationFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sveltos_mgmt_cluster_token_rotation_failures_total",
+		Help: "Total number of management cluster token rotation failures",
+	})
+
+	tokenExpirationSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sveltos_mgmt_cluster_token_expiration_seconds",
+		Help: "Unix timestamp, in seconds, at which the current management cluster token expires",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(tokenRotationsTotal, tokenRotationFailuresTotal, tokenExpirationSeconds)
+}
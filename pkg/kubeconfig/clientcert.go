@@ -0,0 +1,136 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	csrPollInterval = 2 * time.Second
+	csrPollTimeout  = 2 * time.Minute
+)
+
+// RequestClientCertificate creates a CertificateSigningRequest named csrName for commonName
+// in the given organizations, waits for it to be approved and issued by the management
+// cluster's signer, and returns the PEM encoded certificate and private key.
+func RequestClientCertificate(ctx context.Context, clientset kubernetes.Interface,
+	csrName, commonName string, organizations []string, signerName string, logger logr.Logger) (certPEM, keyPEM []byte, err error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	csrTemplate := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: organizations,
+		},
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create CertificateSigningRequest %s", csrName))
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: csrName,
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    csrPEM,
+			SignerName: signerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageClientAuth,
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+			},
+		},
+	}
+
+	created, err := clientset.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// csrName is deterministic (derived from the ServiceAccount), so a previous run's
+		// CSR is still around. This tool is re-invoked on every CronJob run/token rotation,
+		// so delete the stale request and recreate it to get a certificate for this key.
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("CertificateSigningRequest %s already exists, recreating it", csrName))
+		if delErr := clientset.CertificatesV1().CertificateSigningRequests().Delete(ctx, csrName, metav1.DeleteOptions{}); delErr != nil &&
+			!apierrors.IsNotFound(delErr) {
+			return nil, nil, fmt.Errorf("failed to delete stale CertificateSigningRequest %s: %w", csrName, delErr)
+		}
+		created, err = clientset.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CertificateSigningRequest %s: %w", csrName, err)
+	}
+
+	certPEM, err = waitForCertificate(ctx, clientset, created.Name, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+// waitForCertificate polls the CertificateSigningRequest until it is approved and issued, or
+// csrPollTimeout elapses. Approval itself is expected to come from an external approver
+// (cluster-signing controller, security team workflow, etc.), not from this tool.
+func waitForCertificate(ctx context.Context, clientset kubernetes.Interface, name string, logger logr.Logger) ([]byte, error) {
+	deadline := time.Now().Add(csrPollTimeout)
+	for {
+		csr, err := clientset.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range csr.Status.Conditions {
+			c := csr.Status.Conditions[i]
+			if c.Type == certificatesv1.CertificateDenied {
+				return nil, fmt.Errorf("CertificateSigningRequest %s was denied: %s", name, c.Message)
+			}
+			if c.Type == certificatesv1.CertificateFailed {
+				return nil, fmt.Errorf("CertificateSigningRequest %s failed: %s", name, c.Message)
+			}
+		}
+
+		if len(csr.Status.Certificate) > 0 {
+			return bytes.TrimSpace(csr.Status.Certificate), nil
+		}
+
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("CertificateSigningRequest %s not issued yet", name))
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for CertificateSigningRequest %s to be issued", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(csrPollInterval):
+		}
+	}
+}
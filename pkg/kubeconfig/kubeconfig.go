@@ -0,0 +1,166 @@
+// Package kubeconfig builds kubeconfigs for the management cluster ServiceAccount
+// register-mgmt-cluster creates, supporting multiple authentication mechanisms.
+package kubeconfig
+
+import (
+	"sort"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+)
+
+// AuthMode identifies how the generated kubeconfig authenticates to the management cluster.
+type AuthMode string
+
+const (
+	// TokenRequestAuthMode embeds a bound token obtained via the TokenRequest API.
+	TokenRequestAuthMode AuthMode = "token-request"
+	// ServiceAccountTokenAuthMode embeds the long-lived token stored in a
+	// kubernetes.io/service-account-token Secret.
+	ServiceAccountTokenAuthMode AuthMode = "service-account-token"
+	// ClientCertAuthMode embeds a client certificate/key pair issued by the
+	// management cluster's signer.
+	ClientCertAuthMode AuthMode = "client-cert"
+	// ExecAuthMode delegates authentication to an external credential plugin.
+	ExecAuthMode AuthMode = "exec"
+
+	clusterName = "local"
+	contextName = "sveltos-context"
+)
+
+// UsesBearerToken reports whether a kubeconfig built with this auth mode embeds a bearer
+// token that can be periodically refreshed via the TokenRequest API. ClientCertAuthMode and
+// ExecAuthMode carry no such token: enabling TokenRequest-based renewal for them would have
+// Sveltos overwrite the credential the operator explicitly selected.
+func (m AuthMode) UsesBearerToken() bool {
+	switch m {
+	case ClientCertAuthMode, ExecAuthMode:
+		return false
+	default:
+		return true
+	}
+}
+
+// ClusterEndpoint describes how the generated kubeconfig should reach the management cluster.
+type ClusterEndpoint struct {
+	// Server is the API server URL, embedded as is. Callers pass an override endpoint
+	// here when restConfig.Host is an in-cluster ClusterIP unreachable from workload
+	// clusters.
+	Server string
+	// CAData is the PEM encoded certificate authority data.
+	CAData []byte
+	// ProxyURL, if set, is used as the cluster's proxy-url.
+	ProxyURL string
+	// TLSServerName, if set, overrides the server name used to verify the certificate
+	// presented by the API server.
+	TLSServerName string
+}
+
+// Builder assembles kubeconfigs for a given namespace/ServiceAccount pair, sharing the
+// same cluster endpoint across auth modes.
+type Builder struct {
+	Namespace          string
+	ServiceAccountName string
+	Endpoint           ClusterEndpoint
+}
+
+// NewBuilder returns a Builder for the given namespace/ServiceAccount, targeting endpoint.
+func NewBuilder(namespace, serviceAccountName string, endpoint ClusterEndpoint) *Builder {
+	return &Builder{
+		Namespace:          namespace,
+		ServiceAccountName: serviceAccountName,
+		Endpoint:           endpoint,
+	}
+}
+
+func (b *Builder) newConfig(authInfo clientcmdapi.AuthInfo) *clientcmdapi.Config {
+	cfg := clientcmdapi.NewConfig()
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = b.Endpoint.Server
+	cluster.CertificateAuthorityData = b.Endpoint.CAData
+	cluster.ProxyURL = b.Endpoint.ProxyURL
+	cluster.TLSServerName = b.Endpoint.TLSServerName
+	cfg.Clusters[clusterName] = cluster
+
+	cfg.AuthInfos[b.ServiceAccountName] = &authInfo
+
+	context := clientcmdapi.NewContext()
+	context.Cluster = clusterName
+	context.AuthInfo = b.ServiceAccountName
+	context.Namespace = b.Namespace
+	cfg.Contexts[contextName] = context
+
+	cfg.CurrentContext = contextName
+
+	return cfg
+}
+
+// write serializes cfg the same way `kubectl config view` would.
+func write(cfg *clientcmdapi.Config) (string, error) {
+	data, err := clientcmdlatest.Codec.Encode(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// BuildWithToken returns a kubeconfig authenticating with a bearer token. It is used for
+// both TokenRequestAuthMode and ServiceAccountTokenAuthMode: the two differ only in how the
+// caller obtained token, not in the kubeconfig shape.
+func (b *Builder) BuildWithToken(token string) (string, error) {
+	authInfo := *clientcmdapi.NewAuthInfo()
+	authInfo.Token = token
+
+	return write(b.newConfig(authInfo))
+}
+
+// BuildWithClientCert returns a kubeconfig authenticating with a client certificate/key pair.
+func (b *Builder) BuildWithClientCert(certData, keyData []byte) (string, error) {
+	authInfo := *clientcmdapi.NewAuthInfo()
+	authInfo.ClientCertificateData = certData
+	authInfo.ClientKeyData = keyData
+
+	return write(b.newConfig(authInfo))
+}
+
+// ExecConfig describes the external credential plugin to invoke, e.g. `aws eks get-token`
+// or `gcloud config config-helper`.
+type ExecConfig struct {
+	// APIVersion is the client.authentication.k8s.io version the plugin implements.
+	APIVersion string
+	// Command is the plugin binary to exec.
+	Command string
+	// Args are passed to Command.
+	Args []string
+	// Env are additional environment variables set when invoking Command.
+	Env map[string]string
+}
+
+// BuildWithExec returns a kubeconfig that delegates authentication to an external plugin.
+func (b *Builder) BuildWithExec(exec ExecConfig) (string, error) {
+	authInfo := *clientcmdapi.NewAuthInfo()
+
+	// Sort by key so the serialized kubeconfig is deterministic across runs: ranging over
+	// exec.Env directly would otherwise produce a different Secret on every rotation.
+	envNames := make([]string, 0, len(exec.Env))
+	for k := range exec.Env {
+		envNames = append(envNames, k)
+	}
+	sort.Strings(envNames)
+
+	var env []clientcmdapi.ExecEnvVar
+	for _, k := range envNames {
+		env = append(env, clientcmdapi.ExecEnvVar{Name: k, Value: exec.Env[k]})
+	}
+
+	authInfo.Exec = &clientcmdapi.ExecConfig{
+		APIVersion:      exec.APIVersion,
+		Command:         exec.Command,
+		Args:            exec.Args,
+		Env:             env,
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+
+	return write(b.newConfig(authInfo))
+}
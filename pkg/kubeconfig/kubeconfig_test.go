@@ -0,0 +1,118 @@
+package kubeconfig_test
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/kubeconfig"
+)
+
+const (
+	namespace          = "projectsveltos"
+	serviceAccountName = "projectsveltos"
+)
+
+func newBuilder() *kubeconfig.Builder {
+	return kubeconfig.NewBuilder(namespace, serviceAccountName, kubeconfig.ClusterEndpoint{
+		Server: "https://10.0.0.1:6443",
+		CAData: []byte("ca-data"),
+	})
+}
+
+func TestBuildWithToken(t *testing.T) {
+	data, err := newBuilder().BuildWithToken("my-token")
+	if err != nil {
+		t.Fatalf("BuildWithToken returned error: %v", err)
+	}
+
+	cfg, err := clientcmd.Load([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to parse generated kubeconfig: %v", err)
+	}
+
+	authInfo, ok := cfg.AuthInfos[serviceAccountName]
+	if !ok {
+		t.Fatalf("expected AuthInfo %q, got %+v", serviceAccountName, cfg.AuthInfos)
+	}
+	if authInfo.Token != "my-token" {
+		t.Fatalf("expected token %q, got %q", "my-token", authInfo.Token)
+	}
+}
+
+func TestBuildWithClientCert(t *testing.T) {
+	data, err := newBuilder().BuildWithClientCert([]byte("cert-data"), []byte("key-data"))
+	if err != nil {
+		t.Fatalf("BuildWithClientCert returned error: %v", err)
+	}
+
+	cfg, err := clientcmd.Load([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to parse generated kubeconfig: %v", err)
+	}
+
+	authInfo := cfg.AuthInfos[serviceAccountName]
+	if string(authInfo.ClientCertificateData) != "cert-data" {
+		t.Fatalf("expected client-certificate-data %q, got %q", "cert-data", authInfo.ClientCertificateData)
+	}
+	if string(authInfo.ClientKeyData) != "key-data" {
+		t.Fatalf("expected client-key-data %q, got %q", "key-data", authInfo.ClientKeyData)
+	}
+}
+
+func TestBuildWithExec(t *testing.T) {
+	data, err := newBuilder().BuildWithExec(kubeconfig.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    "aws",
+		Args:       []string{"eks", "get-token", "--cluster-name", "my-cluster"},
+		Env:        map[string]string{"AWS_PROFILE": "sveltos"},
+	})
+	if err != nil {
+		t.Fatalf("BuildWithExec returned error: %v", err)
+	}
+
+	if !strings.Contains(data, "aws") || !strings.Contains(data, "get-token") {
+		t.Fatalf("expected exec stanza referencing aws get-token, got %q", data)
+	}
+
+	cfg, err := clientcmd.Load([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to parse generated kubeconfig: %v", err)
+	}
+
+	authInfo := cfg.AuthInfos[serviceAccountName]
+	if authInfo.Exec == nil {
+		t.Fatal("expected AuthInfo.Exec to be set")
+	}
+	if authInfo.Exec.Command != "aws" {
+		t.Fatalf("expected exec command %q, got %q", "aws", authInfo.Exec.Command)
+	}
+}
+
+func TestBuildSetsProxyURLAndTLSServerName(t *testing.T) {
+	builder := kubeconfig.NewBuilder(namespace, serviceAccountName, kubeconfig.ClusterEndpoint{
+		Server:        "https://10.0.0.1:6443",
+		CAData:        []byte("ca-data"),
+		ProxyURL:      "https://proxy.example.com",
+		TLSServerName: "api.example.com",
+	})
+
+	data, err := builder.BuildWithToken("my-token")
+	if err != nil {
+		t.Fatalf("BuildWithToken returned error: %v", err)
+	}
+
+	cfg, err := clientcmd.Load([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to parse generated kubeconfig: %v", err)
+	}
+
+	cluster := cfg.Clusters["local"]
+	if cluster.ProxyURL != "https://proxy.example.com" {
+		t.Fatalf("expected proxy-url %q, got %q", "https://proxy.example.com", cluster.ProxyURL)
+	}
+	if cluster.TLSServerName != "api.example.com" {
+		t.Fatalf("expected tls-server-name %q, got %q", "api.example.com", cluster.TLSServerName)
+	}
+}
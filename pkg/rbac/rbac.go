@@ -0,0 +1,216 @@
+// Package rbac computes the ClusterRole granted to the management cluster ServiceAccount
+// Sveltos uses to pull/apply resources, ranging from a wildcard cluster-admin-equivalent
+// role to one scoped down to exactly what the installed Sveltos components need.
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-logr/logr"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Mode selects how the ClusterRole granted to the management cluster ServiceAccount is
+// computed.
+type Mode string
+
+const (
+	// ClusterAdminMode grants verbs=*, apiGroups=*, resources=*, the tool's original,
+	// effectively cluster-admin behavior.
+	ClusterAdminMode Mode = "cluster-admin"
+	// SveltosScopedMode unions the PolicyRules of the installed Sveltos component
+	// ClusterRoles plus a small set of rules needed for onboarding. This is the
+	// recommended mode for regulated environments.
+	SveltosScopedMode Mode = "sveltos-scoped"
+	// CustomMode reads the ClusterRole's Rules from a user-supplied YAML manifest.
+	CustomMode Mode = "custom"
+)
+
+// sveltosComponentClusterRoles are the ClusterRoles shipped alongside the Sveltos
+// components that act on behalf of the management cluster. Names missing from the
+// cluster (a component not installed) are skipped rather than treated as an error.
+var sveltosComponentClusterRoles = []string{
+	"addon-controller-role",
+	"classifier-role",
+	"sveltoscluster-manager-role",
+	"healthcheck-manager-role",
+	"event-manager-role",
+}
+
+// onboardingRules are the extra permissions register-mgmt-cluster itself needs that are
+// not part of any Sveltos component role: creating the namespace/ServiceAccount it
+// registers, and reading back the kubeconfig Secret it creates.
+var onboardingRules = []rbacv1.PolicyRule{
+	{
+		Verbs:     []string{"create", "get"},
+		APIGroups: []string{""},
+		Resources: []string{"namespaces"},
+	},
+	{
+		Verbs:     []string{"create", "get"},
+		APIGroups: []string{""},
+		Resources: []string{"serviceaccounts"},
+	},
+	{
+		Verbs:     []string{"get"},
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+	},
+}
+
+// Options configures BuildClusterRole.
+type Options struct {
+	Mode Mode
+	// CustomManifestPath is the path to a ClusterRole YAML manifest, used when Mode is
+	// CustomMode.
+	CustomManifestPath string
+}
+
+// BuildClusterRole computes the PolicyRules for the given mode.
+func BuildClusterRole(ctx context.Context, c client.Client, opts Options, logger logr.Logger) ([]rbacv1.PolicyRule, error) {
+	switch opts.Mode {
+	case ClusterAdminMode, "":
+		return []rbacv1.PolicyRule{
+			{
+				Verbs:     []string{"*"},
+				APIGroups: []string{"*"},
+				Resources: []string{"*"},
+			},
+			{
+				Verbs:           []string{"*"},
+				NonResourceURLs: []string{"*"},
+			},
+		}, nil
+	case SveltosScopedMode:
+		return buildSveltosScopedRules(ctx, c, logger)
+	case CustomMode:
+		return rulesFromManifest(opts.CustomManifestPath)
+	default:
+		return nil, fmt.Errorf("unsupported rbac mode %q", opts.Mode)
+	}
+}
+
+func buildSveltosScopedRules(ctx context.Context, c client.Client, logger logr.Logger) ([]rbacv1.PolicyRule, error) {
+	rules := make([]rbacv1.PolicyRule, 0)
+
+	for _, name := range sveltosComponentClusterRoles {
+		role := &rbacv1.ClusterRole{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, role); err != nil {
+			if apierrors.IsNotFound(err) {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("ClusterRole %s not found, skipping", name))
+				continue
+			}
+			return nil, err
+		}
+		rules = append(rules, role.Rules...)
+	}
+
+	rules = append(rules, onboardingRules...)
+
+	return dedupeRules(rules), nil
+}
+
+func rulesFromManifest(path string) ([]rbacv1.PolicyRule, error) {
+	if path == "" {
+		return nil, fmt.Errorf("--custom-clusterrole-path is required when --rbac-mode=%s", CustomMode)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := yaml.Unmarshal(data, clusterRole); err != nil {
+		return nil, fmt.Errorf("failed to parse %s as a ClusterRole: %w", path, err)
+	}
+
+	return clusterRole.Rules, nil
+}
+
+// dedupeRules removes exact duplicate PolicyRules, a likely byproduct of unioning several
+// component roles that already grant overlapping permissions.
+func dedupeRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	seen := make(map[string]bool)
+	result := make([]rbacv1.PolicyRule, 0, len(rules))
+	for _, rule := range rules {
+		key := ruleKey(rule)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, rule)
+	}
+	return result
+}
+
+func ruleKey(rule rbacv1.PolicyRule) string {
+	verbs := append([]string{}, rule.Verbs...)
+	groups := append([]string{}, rule.APIGroups...)
+	resources := append([]string{}, rule.Resources...)
+	names := append([]string{}, rule.ResourceNames...)
+	nonResourceURLs := append([]string{}, rule.NonResourceURLs...)
+	sort.Strings(verbs)
+	sort.Strings(groups)
+	sort.Strings(resources)
+	sort.Strings(names)
+	sort.Strings(nonResourceURLs)
+	return fmt.Sprintf("%v|%v|%v|%v|%v", verbs, groups, resources, names, nonResourceURLs)
+}
+
+// ApplyClusterRole creates clusterRoleName with the given rules, or updates it if the
+// computed rule set differs from what is currently stored, logging a diff line so
+// operators can audit privilege drift.
+func ApplyClusterRole(ctx context.Context, c client.Client, clusterRoleName string,
+	rules []rbacv1.PolicyRule, logger logr.Logger) error {
+
+	current := &rbacv1.ClusterRole{}
+	err := c.Get(ctx, types.NamespacedName{Name: clusterRoleName}, current)
+	if apierrors.IsNotFound(err) {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("Create ClusterRole %s (%d rules)", clusterRoleName, len(rules)))
+		current = &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterRoleName},
+			Rules:      rules,
+		}
+		return c.Create(ctx, current)
+	}
+	if err != nil {
+		return err
+	}
+
+	if rulesEqual(current.Rules, rules) {
+		return nil
+	}
+
+	logger.Info(fmt.Sprintf("ClusterRole %s rule set changed: %d rules -> %d rules",
+		clusterRoleName, len(current.Rules), len(rules)))
+
+	current.Rules = rules
+	return c.Update(ctx, current)
+}
+
+func rulesEqual(a, b []rbacv1.PolicyRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	aKeys := make(map[string]bool, len(a))
+	for _, rule := range a {
+		aKeys[ruleKey(rule)] = true
+	}
+	for _, rule := range b {
+		if !aKeys[ruleKey(rule)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package rbac
+
+import (
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestDedupeRules(t *testing.T) {
+	rules := []rbacv1.PolicyRule{
+		{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+		{Verbs: []string{"list", "get"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+	}
+
+	got := dedupeRules(rules)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 deduplicated rules, got %d: %+v", len(got), got)
+	}
+}
+
+func TestRulesEqualIgnoresOrder(t *testing.T) {
+	a := []rbacv1.PolicyRule{
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+	}
+	b := []rbacv1.PolicyRule{
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+	}
+
+	if !rulesEqual(a, b) {
+		t.Fatal("expected rule sets to be equal regardless of order")
+	}
+}
+
+func TestRulesEqualDetectsDrift(t *testing.T) {
+	a := []rbacv1.PolicyRule{
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+	}
+	b := []rbacv1.PolicyRule{
+		{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+	}
+
+	if rulesEqual(a, b) {
+		t.Fatal("expected rule sets with different verbs to be unequal")
+	}
+}
+
+func TestRulesFromManifestRequiresPath(t *testing.T) {
+	if _, err := rulesFromManifest(""); err == nil {
+		t.Fatal("expected error when no manifest path is supplied")
+	}
+}
@@ -0,0 +1,153 @@
+package registration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/kubeconfig"
+)
+
+// ExistingCredentialsOptions configures "bring your own credentials" registration, for
+// operators who already provision management cluster credentials out-of-band (Vault agent
+// injector, cert-manager, external IdP) and want register-mgmt-cluster to skip
+// ServiceAccount/RBAC/token creation entirely.
+type ExistingCredentialsOptions struct {
+	// KubeconfigSelectorNamespace is the namespace searched for a Secret matching
+	// KubeconfigSelector.
+	KubeconfigSelectorNamespace string
+	// KubeconfigSelector, when set, selects a Secret holding a ready-to-use kubeconfig
+	// under its "kubeconfig" key, e.g. "sveltos.projectsveltos.io/mgmt-kubeconfig=true".
+	KubeconfigSelector string
+	// ExistingServiceAccountNamespace/ExistingServiceAccountName, when set, reuse an
+	// already-provisioned ServiceAccount (with its own RBAC) and only mint a token for it.
+	ExistingServiceAccountNamespace string
+	ExistingServiceAccountName      string
+}
+
+// Enabled reports whether either BYO credentials mode is selected.
+func (o ExistingCredentialsOptions) Enabled() bool {
+	return o.KubeconfigSelector != "" || o.ExistingServiceAccountName != ""
+}
+
+// DisablesTokenRenewal reports whether the resulting credential is not a Sveltos-managed
+// TokenRequest, meaning the SveltosCluster's TokenRequestRenewalOption must stay unset:
+// Sveltos has no way to rotate a credential it did not mint. This only applies to the
+// KubeconfigSelector mode, where the whole kubeconfig is adopted verbatim from an externally
+// managed Secret. The ExistingServiceAccountName mode reuses an operator-provisioned
+// ServiceAccount, but the token itself is still minted by this tool via the TokenRequest API
+// (see kubeconfigFromExistingServiceAccount), so Sveltos can keep renewing it the same way it
+// would for a ServiceAccount it created itself.
+func (o ExistingCredentialsOptions) DisablesTokenRenewal() bool {
+	return o.KubeconfigSelector != ""
+}
+
+// ParseExistingServiceAccount parses the --existing-serviceaccount=<namespace>/<name> flag.
+func ParseExistingServiceAccount(value string) (namespace, name string, err error) {
+	if value == "" {
+		return "", "", nil
+	}
+
+	const nsNameParts = 2
+	parts := strings.SplitN(value, "/", nsNameParts)
+	if len(parts) != nsNameParts || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("--existing-serviceaccount must be in the form <namespace>/<name>, got %q", value)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// GenerateKubeconfigFromExistingCredentials implements the --existing-kubeconfig-selector
+// and --existing-serviceaccount modes, returning a kubeconfig without creating any
+// ServiceAccount/RBAC of its own.
+func GenerateKubeconfigFromExistingCredentials(ctx context.Context, restConfig *rest.Config, c client.Client,
+	expirationSeconds int, caData []byte, opts ExistingCredentialsOptions, logger logr.Logger) (string, error) {
+
+	if opts.KubeconfigSelector != "" {
+		return kubeconfigFromSelector(ctx, c, opts.KubeconfigSelectorNamespace, opts.KubeconfigSelector, logger)
+	}
+
+	return kubeconfigFromExistingServiceAccount(ctx, restConfig, opts.ExistingServiceAccountNamespace,
+		opts.ExistingServiceAccountName, expirationSeconds, caData, logger)
+}
+
+func kubeconfigFromSelector(ctx context.Context, c client.Client, namespace, rawSelector string, logger logr.Logger) (string, error) {
+	selector, err := labels.Parse(rawSelector)
+	if err != nil {
+		return "", fmt.Errorf("invalid --existing-kubeconfig-selector %q: %w", rawSelector, err)
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Looking for Secret matching %q in namespace %s", rawSelector, namespace))
+	secretList := &corev1.SecretList{}
+	if err := c.List(ctx, secretList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "", err
+	}
+
+	switch len(secretList.Items) {
+	case 0:
+		return "", fmt.Errorf("no Secret in namespace %s matches selector %q", namespace, rawSelector)
+	case 1:
+	default:
+		return "", fmt.Errorf("expected exactly one Secret in namespace %s matching selector %q, found %d",
+			namespace, rawSelector, len(secretList.Items))
+	}
+
+	secret := &secretList.Items[0]
+	data, ok := secret.Data[KubeconfigKey]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no %s key", secret.Namespace, secret.Name, KubeconfigKey)
+	}
+
+	if err := validateKubeconfig(data); err != nil {
+		return "", fmt.Errorf("secret %s/%s does not hold a usable kubeconfig: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return string(data), nil
+}
+
+// validateKubeconfig parses data and calls /version against the cluster it points at, to
+// catch stale or malformed BYO credentials before they are adopted.
+func validateKubeconfig(data []byte) error {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	if _, err := discoveryClient.ServerVersion(); err != nil {
+		return fmt.Errorf("failed to reach API server: %w", err)
+	}
+
+	return nil
+}
+
+func kubeconfigFromExistingServiceAccount(ctx context.Context, restConfig *rest.Config, namespace, serviceAccountName string,
+	expirationSeconds int, caData []byte, logger logr.Logger) (string, error) {
+
+	tokenRequest, err := getServiceAccountTokenRequest(ctx, restConfig, namespace, serviceAccountName, expirationSeconds, logger)
+	if err != nil {
+		return "", err
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Get Kubeconfig for existing ServiceAccount %s/%s", namespace, serviceAccountName))
+	builder := kubeconfig.NewBuilder(namespace, serviceAccountName, kubeconfig.ClusterEndpoint{
+		Server: restConfig.Host,
+		CAData: caData,
+	})
+
+	return builder.BuildWithToken(tokenRequest.Token)
+}
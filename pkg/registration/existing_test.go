@@ -0,0 +1,73 @@
+package registration
+
+import "testing"
+
+func TestParseExistingServiceAccount(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		wantNamespace string
+		wantName      string
+		wantErr       bool
+	}{
+		{name: "empty value", value: "", wantNamespace: "", wantName: ""},
+		{name: "valid namespace/name", value: "foo/bar", wantNamespace: "foo", wantName: "bar"},
+		{name: "missing slash", value: "foobar", wantErr: true},
+		{name: "missing name", value: "foo/", wantErr: true},
+		{name: "missing namespace", value: "/bar", wantErr: true},
+	}
+
+	for i := range tests {
+		tt := tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, name, err := ParseExistingServiceAccount(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for value %q", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if namespace != tt.wantNamespace || name != tt.wantName {
+				t.Fatalf("got namespace=%q name=%q, want namespace=%q name=%q",
+					namespace, name, tt.wantNamespace, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestExistingCredentialsOptionsEnabledAndDisablesTokenRenewal(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            ExistingCredentialsOptions
+		wantEnabled     bool
+		wantDisablesRen bool
+	}{
+		{name: "neither set", opts: ExistingCredentialsOptions{}},
+		{
+			name:            "selector set",
+			opts:            ExistingCredentialsOptions{KubeconfigSelector: "foo=bar"},
+			wantEnabled:     true,
+			wantDisablesRen: true,
+		},
+		{
+			name:        "existing service account set",
+			opts:        ExistingCredentialsOptions{ExistingServiceAccountName: "sa"},
+			wantEnabled: true,
+		},
+	}
+
+	for i := range tests {
+		tt := tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Enabled(); got != tt.wantEnabled {
+				t.Fatalf("Enabled() = %v, want %v", got, tt.wantEnabled)
+			}
+			if got := tt.opts.DisablesTokenRenewal(); got != tt.wantDisablesRen {
+				t.Fatalf("DisablesTokenRenewal() = %v, want %v", got, tt.wantDisablesRen)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+// Package registration holds the logic used to onboard the management cluster as a
+// SveltosCluster: creating the ServiceAccount/RBAC/kubeconfig Secret it needs, and
+// patching the corresponding SveltosCluster resource. It is shared by the one-shot CLI
+// entry point and by the ManagementClusterRegistration controller, so both modes stay
+// in sync as new auth modes and RBAC behaviors are added.
+package registration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/kubeconfig"
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/rbac"
+)
+
+const (
+	//nolint: gosec // this is just postfix of the secret name
+	SveltosKubeconfigSecretNamePostfix = "-sveltos-kubeconfig"
+	ProjectSveltos                     = "projectsveltos"
+	TokenExpirationInSeconds           = 7200
+	KubeconfigKey                      = "kubeconfig"
+
+	// RenewalInterval is the interval Sveltos uses to decide when a rotated token is due.
+	// It is fixed at one hour: increasing it would cause issues because Sveltos relies on
+	// this duration to determine when to refresh the token. If this value is larger than
+	// the actual token expiration (set by previously released images), Sveltos might
+	// attempt to use an expired token, leading to authentication failures. This value must
+	// match the duration of the renewed tokens produced by this tool.
+	RenewalInterval = 1 * time.Hour
+)
+
+// AuthOptions groups the flags that control how the generated kubeconfig authenticates to
+// the management cluster.
+type AuthOptions struct {
+	Mode                 kubeconfig.AuthMode
+	ProxyURL             string
+	TLSServerName        string
+	ServerOverride       string
+	ClientCertSignerName string
+	Exec                 kubeconfig.ExecConfig
+}
+
+// GenerateKubeconfigForServiceAccount ensures the namespace/ServiceAccount/ClusterRole/
+// ClusterRoleBinding exist, then returns a kubeconfig authenticating as that ServiceAccount
+// using the mode selected by authOpts.
+func GenerateKubeconfigForServiceAccount(ctx context.Context, restConfig *rest.Config, c client.Client,
+	namespace, serviceAccountName string, expirationSeconds int, caData []byte, authOpts AuthOptions,
+	rbacOpts rbac.Options, logger logr.Logger) (string, error) {
+
+	if err := createNamespace(ctx, c, namespace, logger); err != nil {
+		return "", err
+	}
+	if err := createServiceAccount(ctx, c, namespace, serviceAccountName, logger); err != nil {
+		return "", err
+	}
+	rules, err := rbac.BuildClusterRole(ctx, c, rbacOpts, logger)
+	if err != nil {
+		return "", err
+	}
+	if err := rbac.ApplyClusterRole(ctx, c, ProjectSveltos, rules, logger); err != nil {
+		return "", err
+	}
+	if err := createClusterRoleBinding(ctx, c, ProjectSveltos, ProjectSveltos, namespace, serviceAccountName, logger); err != nil {
+		return "", err
+	}
+
+	server := restConfig.Host
+	if authOpts.ServerOverride != "" {
+		server = authOpts.ServerOverride
+	}
+	builder := kubeconfig.NewBuilder(namespace, serviceAccountName, kubeconfig.ClusterEndpoint{
+		Server:        server,
+		CAData:        caData,
+		ProxyURL:      authOpts.ProxyURL,
+		TLSServerName: authOpts.TLSServerName,
+	})
+
+	switch authOpts.Mode {
+	case kubeconfig.ServiceAccountTokenAuthMode:
+		if err := createSecret(ctx, c, namespace, serviceAccountName, logger); err != nil {
+			return "", err
+		}
+		token, err := getToken(ctx, c, namespace, serviceAccountName)
+		if err != nil {
+			return "", err
+		}
+		logger.V(logs.LogInfo).Info("Get Kubeconfig from ServiceAccount token")
+		return builder.BuildWithToken(token)
+	case kubeconfig.ClientCertAuthMode:
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return "", err
+		}
+		csrName := fmt.Sprintf("%s-%s", namespace, serviceAccountName)
+		commonName := fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccountName)
+		certData, keyData, err := kubeconfig.RequestClientCertificate(ctx, clientset, csrName, commonName,
+			[]string{ProjectSveltos}, authOpts.ClientCertSignerName, logger)
+		if err != nil {
+			return "", err
+		}
+		logger.V(logs.LogInfo).Info("Get Kubeconfig from client certificate")
+		return builder.BuildWithClientCert(certData, keyData)
+	case kubeconfig.ExecAuthMode:
+		if authOpts.Exec.Command == "" {
+			return "", fmt.Errorf("exec command is required when auth mode is %s", kubeconfig.ExecAuthMode)
+		}
+		logger.V(logs.LogInfo).Info("Get Kubeconfig from exec credential plugin")
+		return builder.BuildWithExec(authOpts.Exec)
+	case kubeconfig.TokenRequestAuthMode:
+		tokenRequest, err := getServiceAccountTokenRequest(ctx, restConfig, namespace, serviceAccountName, expirationSeconds, logger)
+		if err != nil {
+			return "", err
+		}
+		logger.V(logs.LogInfo).Info("Get Kubeconfig from TokenRequest")
+		return builder.BuildWithToken(tokenRequest.Token)
+	default:
+		return "", fmt.Errorf("unsupported auth mode %q", authOpts.Mode)
+	}
+}
+
+func createNamespace(ctx context.Context, c client.Client, name string, logger logr.Logger) error {
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create namespace %s", name))
+	currentNs := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	err := c.Create(ctx, currentNs)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create Namespace %s: %v",
+			name, err))
+		return err
+	}
+
+	return nil
+}
+
+func createServiceAccount(ctx context.Context, c client.Client, namespace, name string,
+	logger logr.Logger) error {
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create serviceAccount %s/%s", namespace, name))
+	currentSA := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+
+	err := c.Create(ctx, currentSA)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create ServiceAccount %s/%s: %v",
+			namespace, name, err))
+		return err
+	}
+
+	return nil
+}
+
+func createSecret(ctx context.Context, c client.Client, namespace, saName string,
+	logger logr.Logger) error {
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create Secret %s/%s", namespace, saName))
+	currentSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      saName,
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: saName,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+
+	err := c.Create(ctx, currentSecret)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create Secret %s/%s: %v",
+			namespace, saName, err))
+		return err
+	}
+
+	return nil
+}
+
+func getToken(ctx context.Context, c client.Client, namespace, secretName string) (string, error) {
+	retries := 0
+	const maxRetries = 5
+	for {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretName},
+			secret)
+		if err != nil {
+			if retries < maxRetries {
+				time.Sleep(time.Second)
+				continue
+			}
+			return "", err
+		}
+
+		if secret.Data == nil {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		v, ok := secret.Data["token"]
+		if !ok {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		return string(v), nil
+	}
+}
+
+func createClusterRoleBinding(ctx context.Context, c client.Client,
+	clusterRoleName, clusterRoleBindingName, serviceAccountNamespace, serviceAccountName string, logger logr.Logger) error {
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Create ClusterRoleBinding %s", clusterRoleBindingName))
+	clusterrolebinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterRoleBindingName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.SchemeGroupVersion.Group,
+			Kind:     "ClusterRole",
+			Name:     clusterRoleName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Namespace: serviceAccountNamespace,
+				Name:      serviceAccountName,
+				Kind:      "ServiceAccount",
+				APIGroup:  corev1.SchemeGroupVersion.Group,
+			},
+		},
+	}
+	err := c.Create(ctx, clusterrolebinding)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("Failed to create clusterrolebinding %s: %v",
+			clusterRoleBindingName, err))
+		return err
+	}
+
+	return nil
+}
+
+// getServiceAccountTokenRequest returns token for a serviceaccount
+func getServiceAccountTokenRequest(ctx context.Context, restConfig *rest.Config, serviceAccountNamespace, serviceAccountName string,
+	expirationSeconds int, logger logr.Logger) (*authenticationv1.TokenRequestStatus, error) {
+
+	expiration := int64(expirationSeconds)
+
+	treq := &authenticationv1.TokenRequest{}
+
+	if expirationSeconds != 0 {
+		treq.Spec = authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expiration,
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(logs.LogInfo).Info(
+		fmt.Sprintf("Create Token for ServiceAccount %s/%s", serviceAccountNamespace, serviceAccountName))
+	var tokenRequest *authenticationv1.TokenRequest
+	tokenRequest, err = clientset.CoreV1().ServiceAccounts(serviceAccountNamespace).
+		CreateToken(ctx, serviceAccountName, treq, metav1.CreateOptions{})
+	if err != nil {
+		logger.V(logs.LogInfo).Info(
+			fmt.Sprintf("Failed to create token for ServiceAccount %s/%s: %v",
+				serviceAccountNamespace, serviceAccountName, err))
+		return nil, err
+	}
+
+	return &tokenRequest.Status, nil
+}
+
+// OnboardManagementCluster creates/updates the SveltosCluster representing the management
+// cluster and the Secret holding its kubeconfig. enableRenewal should be false when
+// kubeconfigData was not minted by this tool as a TokenRequest (e.g. BYO credentials via
+// ExistingCredentialsOptions), since Sveltos would otherwise try to rotate a credential it
+// has no way to renew. setSecretOwnerRef adds an owner reference from the kubeconfig Secret
+// to the SveltosCluster, used when the Secret is a copy of externally managed credentials.
+func OnboardManagementCluster(ctx context.Context, c client.Client, clusterNamespace, clusterName, kubeconfigData string,
+	labels map[string]string, enableRenewal, setSecretOwnerRef bool, logger logr.Logger) error {
+
+	err := createNamespace(ctx, c, clusterNamespace, logger)
+	if err != nil {
+		return err
+	}
+
+	err = PatchSveltosCluster(ctx, c, clusterNamespace, clusterName, labels, enableRenewal, logger)
+	if err != nil {
+		return err
+	}
+
+	var ownerRefs []metav1.OwnerReference
+	if setSecretOwnerRef {
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, sveltosCluster); err != nil {
+			return err
+		}
+		ownerRefs = []metav1.OwnerReference{
+			*metav1.NewControllerRef(sveltosCluster, libsveltosv1beta1.GroupVersion.WithKind("SveltosCluster")),
+		}
+	}
+
+	secretName := clusterName + SveltosKubeconfigSecretNamePostfix
+	return PatchSecret(ctx, c, clusterNamespace, secretName, kubeconfigData, ownerRefs, logger)
+}
+
+// PatchSveltosCluster creates, or updates the labels and TokenRequestRenewalOption of, the
+// SveltosCluster representing the management cluster. When enableRenewal is false, the
+// TokenRequestRenewalOption is left unset: Sveltos has no way to rotate a credential it
+// did not mint.
+func PatchSveltosCluster(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	labels map[string]string, enableRenewal bool, logger logr.Logger) error {
+
+	var renewalOption *libsveltosv1beta1.TokenRequestRenewalOption
+	if enableRenewal {
+		renewalOption = &libsveltosv1beta1.TokenRequestRenewalOption{
+			RenewTokenRequestInterval: metav1.Duration{Duration: RenewalInterval},
+		}
+	}
+
+	currentSveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName},
+		currentSveltosCluster)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("Creating SveltosCluster %s/%s", clusterNamespace, clusterName))
+			currentSveltosCluster.Namespace = clusterNamespace
+			currentSveltosCluster.Name = clusterName
+			currentSveltosCluster.Labels = labels
+			currentSveltosCluster.Spec = libsveltosv1beta1.SveltosClusterSpec{
+				TokenRequestRenewalOption: renewalOption,
+			}
+			return c.Create(ctx, currentSveltosCluster)
+		}
+		return err
+	}
+
+	logger.V(logs.LogInfo).Info("Updating SveltosCluster")
+	if currentSveltosCluster.Labels == nil {
+		currentSveltosCluster.Labels = map[string]string{}
+	}
+	for k := range labels {
+		currentSveltosCluster.Labels[k] = labels[k]
+	}
+
+	currentSveltosCluster.Spec = libsveltosv1beta1.SveltosClusterSpec{
+		TokenRequestRenewalOption: renewalOption,
+	}
+	return c.Update(ctx, currentSveltosCluster)
+}
+
+// PatchSecret creates, or updates the kubeconfig data of, the Secret the SveltosCluster
+// references for the management cluster's kubeconfig.
+func PatchSecret(ctx context.Context, c client.Client, clusterNamespace, secretName, kubeconfigData string,
+	ownerRefs []metav1.OwnerReference, logger logr.Logger) error {
+
+	currentSecret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: secretName}, currentSecret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("Creating Secret %s/%s", clusterNamespace, secretName))
+			currentSecret.Namespace = clusterNamespace
+			currentSecret.Name = secretName
+			currentSecret.OwnerReferences = ownerRefs
+			currentSecret.Data = map[string][]byte{KubeconfigKey: []byte(kubeconfigData)}
+			return c.Create(ctx, currentSecret)
+		}
+		return err
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("Updating Secret %s/%s", clusterNamespace, secretName))
+	if len(ownerRefs) > 0 {
+		currentSecret.OwnerReferences = ownerRefs
+	}
+	currentSecret.Data = map[string][]byte{
+		KubeconfigKey: []byte(kubeconfigData),
+	}
+
+	return c.Update(ctx, currentSecret)
+}
+
+// StringToMap parses a `<key1=value1,key2=value2>` string into a map, as accepted by the
+// --labels flag.
+func StringToMap(data string) (map[string]string, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	const keyValueLength = 2
+	result := make(map[string]string)
+	for _, pair := range strings.Split(data, ",") {
+		kv := strings.Split(pair, "=")
+		if len(kv) != keyValueLength {
+			return nil, fmt.Errorf("invalid key-value pair format: %s", pair)
+		}
+		key := strings.TrimSpace(kv[0])
+		value := strings.TrimSpace(kv[1])
+		result[key] = value
+	}
+	return result, nil
+}
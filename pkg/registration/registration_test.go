@@ -0,0 +1,44 @@
+package registration_test
+
+import (
+	"testing"
+
+	"github.com/projectsveltos/register-mgmt-cluster/pkg/registration"
+)
+
+func TestStringToMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: nil},
+		{name: "single pair", input: "k1=v1", want: map[string]string{"k1": "v1"}},
+		{name: "multiple pairs with spacing", input: "k1=v1, k2 = v2", want: map[string]string{"k1": "v1", "k2": "v2"}},
+		{name: "malformed pair", input: "k1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registration.StringToMap(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for input %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Fatalf("expected %s=%s, got %s=%s", k, v, k, got[k])
+				}
+			}
+		})
+	}
+}